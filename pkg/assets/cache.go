@@ -0,0 +1,187 @@
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"time"
+)
+
+// fingerprintedAssetPattern matches static asset filenames that embed a content hash,
+// e.g. "app.3f2a9c1e.js" or "main.a1b2c3d4e5f6.css". These are safe to cache forever
+// since a content change always produces a new path.
+var fingerprintedAssetPattern = regexp.MustCompile(`\.[0-9a-f]{8,32}\.[a-zA-Z0-9]+$`)
+
+// noCacheAssets are served even when unchanged, since they are the entry points that
+// decide which fingerprinted assets to load next.
+var noCacheAssets = map[string]bool{
+	"config.js":  true,
+	"index.html": true,
+}
+
+// assetVariant is one compressed (or identity) representation of a cached asset.
+type assetVariant struct {
+	data []byte
+	etag string // strong ETag: the quoted hex SHA-256 of data
+}
+
+// cachedAsset holds every representation of a single Asset(...) path that
+// NewAssetCache precomputed at startup.
+type cachedAsset struct {
+	name     string
+	modTime  time.Time
+	variants map[Encoding]assetVariant
+}
+
+// AssetCache holds, for every known asset path, a raw and a gzip/brotli/zstd
+// compressed representation computed once at startup so that request handling never
+// pays compression cost and can rely on strong, content-derived ETags.
+type AssetCache struct {
+	assets map[string]*cachedAsset
+}
+
+// NewAssetCache compresses every asset in names (as returned by AssetNames()) with
+// each scheme in encodings and computes a strong ETag for every representation. A nil
+// or empty encodings defaults to br, zstd, and gzip.
+func NewAssetCache(names []string, encodings []Encoding) (*AssetCache, error) {
+	if len(encodings) == 0 {
+		encodings = defaultPreferredEncodings
+	}
+
+	pools := newEncoderPools()
+	cache := &AssetCache{assets: make(map[string]*cachedAsset, len(names))}
+
+	for _, name := range names {
+		raw, err := Asset(name)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &cachedAsset{
+			name:     name,
+			modTime:  assetModTime(name),
+			variants: map[Encoding]assetVariant{
+				EncodingIdentity: newAssetVariant(raw),
+			},
+		}
+
+		for _, encoding := range encodings {
+			compressed, err := compressAll(pools, encoding, raw)
+			if err != nil {
+				return nil, err
+			}
+			entry.variants[encoding] = newAssetVariant(compressed)
+		}
+
+		cache.assets[name] = entry
+	}
+
+	return cache, nil
+}
+
+// assetModTime returns the asset's recorded modification time via AssetInfo, falling
+// back to the zero time (which http.ServeContent treats as "no Last-Modified") if the
+// generated bindata does not carry one.
+func assetModTime(name string) time.Time {
+	info, err := AssetInfo(name)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+func newAssetVariant(data []byte) assetVariant {
+	sum := sha256.Sum256(data)
+	return assetVariant{data: data, etag: `"` + hex.EncodeToString(sum[:]) + `"`}
+}
+
+// compressAll runs the full payload through a single one-shot encoder of the given
+// scheme. This only runs at startup, so pool reuse matters less than correctness.
+func compressAll(pools *encoderPools, encoding Encoding, raw []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	newEncoder := pools.newEncoderFor(encoding)
+	enc := newEncoder(&buf)
+	if _, err := enc.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// get returns the cached representations for an asset path (no leading slash), and
+// whether it is present. Unexported: cachedAsset has no exported surface, so this is
+// only useful to CacheControlHandler in this same package.
+func (c *AssetCache) get(name string) (*cachedAsset, bool) {
+	entry, ok := c.assets[name]
+	return entry, ok
+}
+
+// bestVariant returns the representation of entry that best matches the request's
+// Accept-Encoding header, preferring compressed representations in preferredOrder.
+func (entry *cachedAsset) bestVariant(acceptEncoding string, preferredOrder []Encoding) (Encoding, assetVariant) {
+	var available []Encoding
+	for _, encoding := range preferredOrder {
+		if _, ok := entry.variants[encoding]; ok {
+			available = append(available, encoding)
+		}
+	}
+	encoding := negotiateEncoding(acceptEncoding, available)
+	return encoding, entry.variants[encoding]
+}
+
+// CacheControlHandler serves any path present in cache directly, with a strong,
+// per-representation ETag and full If-None-Match / If-Modified-Since / Range /
+// If-Range support via http.ServeContent. Fingerprinted asset paths are marked
+// immutable for a year; config.js and index.html are marked no-cache, no-store. Paths not in
+// cache fall through to h unchanged.
+func CacheControlHandler(cache *AssetCache, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := trimLeadingSlash(r.URL.Path)
+
+		entry, ok := cache.get(name)
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding, variant := entry.bestVariant(r.Header.Get("Accept-Encoding"), defaultPreferredEncodings)
+		if encoding != EncodingIdentity {
+			w.Header().Set("Content-Encoding", string(encoding))
+		}
+		if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+			w.Header().Set("Content-Type", ctype)
+		}
+		w.Header().Set("ETag", variant.etag)
+		w.Header().Set("Cache-Control", cacheControlFor(name))
+
+		http.ServeContent(w, r, name, entry.modTime, bytes.NewReader(variant.data))
+	})
+}
+
+// cacheControlFor returns the Cache-Control value for an asset path: index pages and
+// the runtime config are never stored (no-cache, no-store), fingerprinted bundles are cached for a
+// year as immutable, and everything else gets a conservative no-cache.
+func cacheControlFor(name string) string {
+	base := path.Base(name)
+	if noCacheAssets[base] {
+		return "no-cache, no-store"
+	}
+	if fingerprintedAssetPattern.MatchString(base) {
+		return "public, max-age=31536000, immutable"
+	}
+	return "no-cache"
+}
+
+func trimLeadingSlash(p string) string {
+	if len(p) > 0 && p[0] == '/' {
+		return p[1:]
+	}
+	return p
+}
@@ -0,0 +1,46 @@
+package assets
+
+import "testing"
+
+func TestCacheControlFor(t *testing.T) {
+	cases := []struct {
+		name string
+		want string
+	}{
+		{"config.js", "no-cache, no-store"},
+		{"index.html", "no-cache, no-store"},
+		{"scripts/app.3f2a9c1e.js", "public, max-age=31536000, immutable"},
+		{"styles/main.css", "no-cache"},
+	}
+
+	for _, c := range cases {
+		if got := cacheControlFor(c.name); got != c.want {
+			t.Errorf("cacheControlFor(%q) = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCachedAssetBestVariant(t *testing.T) {
+	raw := []byte("hello world")
+	entry := &cachedAsset{
+		name: "app.js",
+		variants: map[Encoding]assetVariant{
+			EncodingIdentity: newAssetVariant(raw),
+			EncodingGzip:     newAssetVariant([]byte("gzipped")),
+			EncodingBrotli:   newAssetVariant([]byte("brotli")),
+		},
+	}
+
+	encoding, variant := entry.bestVariant("br, gzip", defaultPreferredEncodings)
+	if encoding != EncodingBrotli {
+		t.Errorf("expected brotli to be preferred, got %q", encoding)
+	}
+	if variant.etag == "" {
+		t.Errorf("expected non-empty etag for selected variant")
+	}
+
+	encoding, _ = entry.bestVariant("", defaultPreferredEncodings)
+	if encoding != EncodingIdentity {
+		t.Errorf("expected identity when no Accept-Encoding is sent, got %q", encoding)
+	}
+}
@@ -0,0 +1,379 @@
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Encoding identifies a single content-coding this package knows how to produce.
+type Encoding string
+
+const (
+	EncodingBrotli   Encoding = "br"
+	EncodingZstd     Encoding = "zstd"
+	EncodingGzip     Encoding = "gzip"
+	EncodingIdentity Encoding = "identity"
+)
+
+// defaultPreferredEncodings is the order encodings are chosen in when the client's
+// Accept-Encoding header does not otherwise disambiguate between acceptable candidates.
+var defaultPreferredEncodings = []Encoding{EncodingBrotli, EncodingZstd, EncodingGzip}
+
+// defaultMinCompressionSize is the smallest response body, in bytes, worth paying the
+// compression overhead for. Smaller bodies are served as-is.
+const defaultMinCompressionSize = 860
+
+// defaultSkippedContentTypePrefixes lists content types that are already compressed (or
+// otherwise not worth compressing again) and should be served without a content-coding.
+var defaultSkippedContentTypePrefixes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/font-woff",
+	"application/font-woff2",
+}
+
+// CompressionMetricsRecorder receives the raw and compressed size of every response
+// CompressionHandler actually compresses, so callers can track compression ratio.
+type CompressionMetricsRecorder interface {
+	ObserveCompression(scheme string, rawBytes, compressedBytes int)
+}
+
+// CompressionOptions configures the encodings CompressionHandler is willing to negotiate.
+type CompressionOptions struct {
+	// PreferredEncodings is consulted, in order, when the client accepts more than one
+	// encoding with an equal q-value. Defaults to br, zstd, gzip.
+	PreferredEncodings []Encoding
+	// MinSize is the smallest response, in bytes, that will be compressed. Defaults to 860.
+	MinSize int
+	// SkipContentTypePrefixes lists response Content-Type prefixes that are never compressed.
+	SkipContentTypePrefixes []string
+	// MetricsRecorder, if set, is notified of the raw/compressed size of every
+	// response that gets compressed.
+	MetricsRecorder CompressionMetricsRecorder
+}
+
+func (o CompressionOptions) withDefaults() CompressionOptions {
+	if len(o.PreferredEncodings) == 0 {
+		o.PreferredEncodings = defaultPreferredEncodings
+	}
+	if o.MinSize == 0 {
+		o.MinSize = defaultMinCompressionSize
+	}
+	if o.SkipContentTypePrefixes == nil {
+		o.SkipContentTypePrefixes = defaultSkippedContentTypePrefixes
+	}
+	return o
+}
+
+// encoderPools holds a sync.Pool per Encoding so concurrent requests reuse
+// encoder state instead of allocating a fresh one per response.
+type encoderPools struct {
+	gzip   sync.Pool
+	brotli sync.Pool
+	zstd   sync.Pool
+}
+
+func newEncoderPools() *encoderPools {
+	p := &encoderPools{}
+	p.gzip.New = func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.BestSpeed)
+		return w
+	}
+	p.brotli.New = func() interface{} {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	}
+	p.zstd.New = func() interface{} {
+		w, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		return w
+	}
+	return p
+}
+
+// compressionResponseWriter buffers the response until it has enough bytes (or the
+// handler is done writing) to decide whether compression is worthwhile, sniffing the
+// Content-Type from the buffered bytes when none was set. Once the decision
+// is made the buffered bytes are flushed through the chosen path exactly once.
+type compressionResponseWriter struct {
+	http.ResponseWriter
+	encoding     Encoding
+	newEncoder   func(io.Writer) io.WriteCloser
+	minSize      int
+	skipPrefixes []string
+	recorder     CompressionMetricsRecorder
+
+	buf      bytes.Buffer
+	status   int
+	decided  bool
+	compress bool
+	encoder  io.WriteCloser
+	rawBytes int64
+	outCount *countingWriter
+}
+
+func (w *compressionResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *compressionResponseWriter) Write(b []byte) (int, error) {
+	w.rawBytes += int64(len(b))
+
+	if w.decided {
+		if w.compress {
+			return w.encoder.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf.Write(b)
+	if w.buf.Len() < w.minSize {
+		return len(b), nil
+	}
+	if err := w.decide(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// decide sniffs the content type (if not already set) and the buffered size so far to
+// pick whether this response will be compressed, then flushes the buffer accordingly,
+// returning any error writing the flushed bytes out. A response that already carries a
+// Content-Encoding (e.g. CacheControlHandler serving a precomputed gzip/brotli/zstd
+// asset variant) is left untouched rather than compressed a second time.
+func (w *compressionResponseWriter) decide() error {
+	contentType := w.Header().Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(w.buf.Bytes())
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	alreadyEncoded := w.Header().Get("Content-Encoding") != ""
+	w.compress = !alreadyEncoded && w.buf.Len() >= w.minSize && !skipCompression(contentType, w.skipPrefixes)
+	if w.compress {
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Encoding", string(w.encoding))
+	}
+	w.writeHeader()
+
+	var err error
+	if w.compress {
+		w.outCount = &countingWriter{w: w.ResponseWriter}
+		w.encoder = w.newEncoder(w.outCount)
+		_, err = w.encoder.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	w.decided = true
+	return err
+}
+
+// countingWriter counts bytes written through it without altering them.
+type countingWriter struct {
+	w     io.Writer
+	count int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.count += int64(n)
+	return n, err
+}
+
+func (w *compressionResponseWriter) writeHeader() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *compressionResponseWriter) Close() error {
+	var err error
+	if !w.decided {
+		err = w.decide()
+	}
+	if w.encoder != nil {
+		if closeErr := w.encoder.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if w.compress && w.recorder != nil {
+		w.recorder.ObserveCompression(string(w.encoding), int(w.rawBytes), int(w.outCount.count))
+	}
+	return err
+}
+
+// CompressionHandler wraps a http.Handler to support transparent gzip, Brotli, and
+// Zstandard encoding, negotiated from the full Accept-Encoding header (including
+// q-values, "identity;q=0", and "*"). It replaces the old gzip-only GzipHandler.
+func CompressionHandler(h http.Handler) http.Handler {
+	return CompressionHandlerWithOptions(CompressionOptions{}, h)
+}
+
+// CompressionHandlerWithOptions is CompressionHandler with caller-supplied tuning.
+func CompressionHandlerWithOptions(opts CompressionOptions, h http.Handler) http.Handler {
+	opts = opts.withDefaults()
+	pools := newEncoderPools()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), opts.PreferredEncodings)
+		if encoding == EncodingIdentity {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		// Normalize the Accept-Encoding header for improved caching of any further
+		// encoding decisions made downstream.
+		r.Header.Set("Accept-Encoding", string(encoding))
+
+		cw := &compressionResponseWriter{
+			ResponseWriter: w,
+			encoding:       encoding,
+			minSize:        opts.MinSize,
+			skipPrefixes:   opts.SkipContentTypePrefixes,
+			newEncoder:     pools.newEncoderFor(encoding),
+			recorder:       opts.MetricsRecorder,
+		}
+		defer cw.Close()
+
+		h.ServeHTTP(cw, r)
+	})
+}
+
+// newEncoderFor returns a constructor that checks out a pooled encoder for scheme,
+// resets it onto dst, and returns it to the pool when closed.
+func (p *encoderPools) newEncoderFor(scheme Encoding) func(io.Writer) io.WriteCloser {
+	switch scheme {
+	case EncodingGzip:
+		return func(dst io.Writer) io.WriteCloser {
+			gz := p.gzip.Get().(*gzip.Writer)
+			gz.Reset(dst)
+			return &pooledWriteCloser{WriteCloser: gz, release: func() { p.gzip.Put(gz) }}
+		}
+	case EncodingBrotli:
+		return func(dst io.Writer) io.WriteCloser {
+			br := p.brotli.Get().(*brotli.Writer)
+			br.Reset(dst)
+			return &pooledWriteCloser{WriteCloser: br, release: func() { p.brotli.Put(br) }}
+		}
+	case EncodingZstd:
+		return func(dst io.Writer) io.WriteCloser {
+			zw := p.zstd.Get().(*zstd.Encoder)
+			zw.Reset(dst)
+			return &pooledWriteCloser{WriteCloser: zw, release: func() { p.zstd.Put(zw) }}
+		}
+	default:
+		return nil
+	}
+}
+
+// pooledWriteCloser returns its underlying encoder to its sync.Pool once closed.
+type pooledWriteCloser struct {
+	io.WriteCloser
+	release func()
+}
+
+func (p *pooledWriteCloser) Close() error {
+	err := p.WriteCloser.Close()
+	p.release()
+	return err
+}
+
+// skipCompression reports whether a response of the given content type should bypass
+// compression entirely, e.g. because it is already compressed.
+func skipCompression(contentType string, skipPrefixes []string) bool {
+	for _, prefix := range skipPrefixes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptedEncoding is one comma-separated member of an Accept-Encoding header.
+type acceptedEncoding struct {
+	scheme Encoding
+	q      float64
+}
+
+// negotiateEncoding parses an Accept-Encoding header per RFC 7231 7.1.4 (q-values,
+// "identity;q=0", and "*") and returns the best scheme from preferred that the client
+// will accept, or EncodingIdentity if none match.
+func negotiateEncoding(header string, preferred []Encoding) Encoding {
+	if header == "" {
+		return EncodingIdentity
+	}
+
+	accepted := map[Encoding]float64{}
+	var wildcardQ float64 = -1
+
+	for _, part := range strings.Split(header, ",") {
+		scheme, q := parseAcceptedEncoding(part)
+		switch scheme {
+		case "":
+			continue
+		case "*":
+			wildcardQ = q
+		case EncodingIdentity:
+			// identity;q=0 only matters when nothing else is acceptable either, which
+			// falls out of the empty-candidates check below.
+		default:
+			accepted[scheme] = q
+		}
+	}
+
+	var candidates []acceptedEncoding
+	for _, scheme := range preferred {
+		if q, ok := accepted[scheme]; ok {
+			if q > 0 {
+				candidates = append(candidates, acceptedEncoding{scheme, q})
+			}
+		} else if wildcardQ > 0 {
+			candidates = append(candidates, acceptedEncoding{scheme, wildcardQ})
+		}
+	}
+	if len(candidates) == 0 {
+		return EncodingIdentity
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].scheme
+}
+
+// parseAcceptedEncoding splits a single "gzip;q=0.8" style token into its coding name
+// and q-value, defaulting to q=1 when unspecified.
+func parseAcceptedEncoding(token string) (Encoding, float64) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", 0
+	}
+	fields := strings.Split(token, ";")
+	name := Encoding(strings.ToLower(strings.TrimSpace(fields[0])))
+	q := 1.0
+	for _, param := range fields[1:] {
+		param = strings.TrimSpace(param)
+		if !strings.HasPrefix(param, "q=") {
+			continue
+		}
+		if parsed, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+			q = parsed
+		}
+	}
+	return name, q
+}
@@ -0,0 +1,124 @@
+package assets
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	preferred := []Encoding{EncodingBrotli, EncodingZstd, EncodingGzip}
+
+	cases := []struct {
+		header string
+		want   Encoding
+	}{
+		{"", EncodingIdentity},
+		{"gzip", EncodingGzip},
+		{"br, gzip", EncodingBrotli},
+		{"br;q=0.1, gzip;q=0.9", EncodingGzip},
+		{"*", EncodingBrotli},
+		{"identity;q=0", EncodingIdentity},
+		{"deflate", EncodingIdentity},
+	}
+
+	for _, c := range cases {
+		if got := negotiateEncoding(c.header, preferred); got != c.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", c.header, got, c.want)
+		}
+	}
+}
+
+func TestParseAcceptedEncoding(t *testing.T) {
+	cases := []struct {
+		token      string
+		wantScheme Encoding
+		wantQ      float64
+	}{
+		{"gzip", EncodingGzip, 1},
+		{"gzip;q=0.5", EncodingGzip, 0.5},
+		{" br ; q=0.8 ", EncodingBrotli, 0.8},
+		{"", "", 0},
+	}
+
+	for _, c := range cases {
+		scheme, q := parseAcceptedEncoding(c.token)
+		if scheme != c.wantScheme || q != c.wantQ {
+			t.Errorf("parseAcceptedEncoding(%q) = (%q, %v), want (%q, %v)", c.token, scheme, q, c.wantScheme, c.wantQ)
+		}
+	}
+}
+
+// TestCompressionResponseWriterSkipsAlreadyEncoded guards against compressing a
+// response a second time when an upstream handler (e.g. CacheControlHandler serving a
+// precomputed variant) already set Content-Encoding.
+func TestCompressionResponseWriterSkipsAlreadyEncoded(t *testing.T) {
+	rec := httptest.NewRecorder()
+	body := make([]byte, defaultMinCompressionSize+1)
+	for i := range body {
+		body[i] = 'a'
+	}
+
+	cw := &compressionResponseWriter{
+		ResponseWriter: rec,
+		encoding:       EncodingGzip,
+		minSize:        defaultMinCompressionSize,
+		skipPrefixes:   defaultSkippedContentTypePrefixes,
+		newEncoder:     newEncoderPools().newEncoderFor(EncodingGzip),
+	}
+	cw.Header().Set("Content-Encoding", "gzip")
+	cw.Header().Set("Content-Type", "application/octet-stream")
+
+	if _, err := cw.Write(body); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	if rec.Code != 0 && rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+	if got := rec.Body.Bytes(); string(got) != string(body) {
+		t.Errorf("body was re-compressed; got %d bytes, want the original %d raw bytes", len(got), len(body))
+	}
+}
+
+// failingResponseWriter simulates a client disconnecting mid-response: every Write
+// after the headers fails.
+type failingResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (failingResponseWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write: broken pipe")
+}
+
+// TestCompressionResponseWriterPropagatesWriteError guards against a write failure on
+// the first flushed chunk (e.g. a client disconnecting) being silently swallowed
+// instead of reaching the caller, as it was before decide()'s error was plumbed through.
+func TestCompressionResponseWriterPropagatesWriteError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	cw := &compressionResponseWriter{
+		ResponseWriter: failingResponseWriter{rec},
+		encoding:       EncodingGzip,
+		minSize:        defaultMinCompressionSize,
+		skipPrefixes:   defaultSkippedContentTypePrefixes,
+		newEncoder:     newEncoderPools().newEncoderFor(EncodingGzip),
+	}
+	// Pre-set Content-Encoding so decide() takes the pass-through (non-compress) path,
+	// which writes directly to the failing ResponseWriter rather than buffering inside
+	// a gzip writer first.
+	cw.Header().Set("Content-Encoding", "gzip")
+	cw.Header().Set("Content-Type", "application/octet-stream")
+
+	body := make([]byte, defaultMinCompressionSize+1)
+	for i := range body {
+		body[i] = 'a'
+	}
+
+	if _, err := cw.Write(body); err == nil {
+		t.Fatalf("expected Write to propagate the underlying write error, got nil")
+	}
+}
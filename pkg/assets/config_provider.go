@@ -0,0 +1,288 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/fsnotify/fsnotify"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ConfigProvider supplies the WebConsoleConfig that config.js/config.json render. It is
+// called on every request, so implementations must be cheap; the file and ConfigMap
+// providers keep their current value in an atomic.Value updated from a background watch.
+type ConfigProvider interface {
+	Get(ctx context.Context) (WebConsoleConfig, error)
+}
+
+// StaticConfigProvider always returns the same config, for deployments that don't need
+// to change OAuth endpoints or feature flags without a restart.
+type StaticConfigProvider struct {
+	config WebConsoleConfig
+}
+
+// NewStaticConfigProvider wraps a fixed WebConsoleConfig as a ConfigProvider.
+func NewStaticConfigProvider(config WebConsoleConfig) *StaticConfigProvider {
+	return &StaticConfigProvider{config: config}
+}
+
+func (p *StaticConfigProvider) Get(ctx context.Context) (WebConsoleConfig, error) {
+	return p.config, nil
+}
+
+// FileConfigProvider reads WebConsoleConfig from a JSON or YAML file (detected by
+// extension) and reloads it whenever fsnotify reports the file changed.
+type FileConfigProvider struct {
+	path    string
+	current atomic.Value // WebConsoleConfig
+}
+
+// NewFileConfigProvider loads path once synchronously, then starts a goroutine that
+// reloads it on every fsnotify write/create/rename event until ctx is cancelled.
+func NewFileConfigProvider(ctx context.Context, path string) (*FileConfigProvider, error) {
+	p := &FileConfigProvider{path: path}
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	go p.watch(ctx, watcher)
+	return p, nil
+}
+
+func (p *FileConfigProvider) watch(ctx context.Context, watcher *fsnotify.Watcher) {
+	defer watcher.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(p.path) {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			if err := p.reload(); err != nil {
+				DefaultLogger.Errorf(ctx, "Unable to reload web console config from %s: %v", p.path, err)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			DefaultLogger.Errorf(ctx, "Error watching web console config file %s: %v", p.path, err)
+		}
+	}
+}
+
+func (p *FileConfigProvider) reload() error {
+	data, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+
+	var config WebConsoleConfig
+	if strings.HasSuffix(p.path, ".yaml") || strings.HasSuffix(p.path, ".yml") {
+		err = yaml.Unmarshal(data, &config)
+	} else {
+		err = json.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to parse web console config %s: %v", p.path, err)
+	}
+
+	p.current.Store(config)
+	return nil
+}
+
+func (p *FileConfigProvider) Get(ctx context.Context) (WebConsoleConfig, error) {
+	return p.current.Load().(WebConsoleConfig), nil
+}
+
+// ConfigMapConfigProvider reads WebConsoleConfig from a single data key of a
+// ConfigMap, kept current via a client-go shared informer so updates made through the
+// Kubernetes API are picked up without a server restart. Secrets are not watched.
+type ConfigMapConfigProvider struct {
+	dataKey string
+	current atomic.Value // WebConsoleConfig
+}
+
+// NewConfigMapConfigProvider starts an informer for the named ConfigMap in namespace
+// and keeps dataKey's contents (JSON or YAML) parsed into an atomically-readable
+// WebConsoleConfig. It blocks until the informer's cache has synced once.
+func NewConfigMapConfigProvider(ctx context.Context, client kubernetes.Interface, namespace, name, dataKey string) (*ConfigMapConfigProvider, error) {
+	p := &ConfigMapConfigProvider{dataKey: dataKey}
+
+	fieldSelector := fmt.Sprintf("metadata.name=%s", name)
+	informer := cache.NewSharedInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = fieldSelector
+				return client.CoreV1().ConfigMaps(namespace).List(ctx, options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = fieldSelector
+				return client.CoreV1().ConfigMaps(namespace).Watch(ctx, options)
+			},
+		},
+		&corev1.ConfigMap{},
+		10*time.Minute,
+	)
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { p.updateFrom(obj) },
+		UpdateFunc: func(_, obj interface{}) { p.updateFrom(obj) },
+	})
+
+	go informer.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return nil, fmt.Errorf("timed out waiting for ConfigMap %s/%s informer to sync", namespace, name)
+	}
+
+	return p, nil
+}
+
+func (p *ConfigMapConfigProvider) updateFrom(obj interface{}) {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+	raw, ok := configMap.Data[p.dataKey]
+	if !ok {
+		DefaultLogger.Errorf(context.Background(), "ConfigMap %s/%s has no key %q", configMap.Namespace, configMap.Name, p.dataKey)
+		return
+	}
+
+	var config WebConsoleConfig
+	if err := yaml.Unmarshal([]byte(raw), &config); err != nil {
+		DefaultLogger.Errorf(context.Background(), "Unable to parse web console config from ConfigMap %s/%s: %v", configMap.Namespace, configMap.Name, err)
+		return
+	}
+	p.current.Store(config)
+}
+
+func (p *ConfigMapConfigProvider) Get(ctx context.Context) (WebConsoleConfig, error) {
+	v := p.current.Load()
+	if v == nil {
+		return WebConsoleConfig{}, fmt.Errorf("web console config has not synced yet")
+	}
+	return v.(WebConsoleConfig), nil
+}
+
+// configSnapshot is the fully-rendered form of a WebConsoleConfig, cached so that
+// repeated requests for the same underlying config never re-render the template or
+// re-hash the output.
+type configSnapshot struct {
+	config WebConsoleConfig
+	js     []byte
+	json   []byte
+	etag   string
+}
+
+func renderConfigSnapshot(config WebConsoleConfig) (*configSnapshot, error) {
+	var js bytes.Buffer
+	if err := configTemplate.Execute(&js, config); err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(jsonBytes)
+	return &configSnapshot{
+		config: config,
+		js:     js.Bytes(),
+		json:   jsonBytes,
+		etag:   `"` + hex.EncodeToString(sum[:]) + `"`,
+	}, nil
+}
+
+// GeneratedConfigHandler serves config.js (for <script> bootstrapping) and config.json
+// (for SPA fetch-based bootstrapping) from provider, re-rendering only when the
+// provider's value actually changes so the hot path is a lock-free atomic load.
+func GeneratedConfigHandler(provider ConfigProvider, h http.Handler) http.Handler {
+	var cached atomic.Value // *configSnapshot
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urlPath := strings.TrimPrefix(r.URL.Path, "/")
+		if urlPath != "config.js" && urlPath != "config.json" {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		config, err := provider.Get(r.Context())
+		if err != nil {
+			DefaultLogger.Errorf(r.Context(), "Unable to load web console config: %v", err)
+			http.Error(w, "unable to load web console config", http.StatusInternalServerError)
+			return
+		}
+
+		snapshot, _ := cached.Load().(*configSnapshot)
+		if snapshot == nil || !configsEqual(snapshot.config, config) {
+			snapshot, err = renderConfigSnapshot(config)
+			if err != nil {
+				DefaultLogger.Errorf(r.Context(), "Unable to render web console config: %v", err)
+				http.Error(w, "unable to render web console config", http.StatusInternalServerError)
+				return
+			}
+			cached.Store(snapshot)
+		}
+
+		w.Header().Set("Cache-Control", "no-cache, no-store")
+		w.Header().Set("ETag", snapshot.etag)
+		if r.Header.Get("If-None-Match") == snapshot.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		if urlPath == "config.json" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(snapshot.json)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Write(snapshot.js)
+	})
+}
+
+// configsEqual compares two configs by their JSON encoding, which is cheap relative to
+// re-rendering the template and sufficient since WebConsoleConfig is a plain data struct.
+func configsEqual(a, b WebConsoleConfig) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return bytes.Equal(aJSON, bJSON)
+}
@@ -0,0 +1,16 @@
+package assets
+
+import "testing"
+
+func TestConfigsEqual(t *testing.T) {
+	a := WebConsoleConfig{MasterAddr: "host:1", Extensions: map[string]interface{}{"foo": "bar"}}
+	b := WebConsoleConfig{MasterAddr: "host:1", Extensions: map[string]interface{}{"foo": "bar"}}
+	c := WebConsoleConfig{MasterAddr: "host:2"}
+
+	if !configsEqual(a, b) {
+		t.Errorf("expected identical configs to be equal")
+	}
+	if configsEqual(a, c) {
+		t.Errorf("expected different configs to be unequal")
+	}
+}
@@ -2,83 +2,16 @@ package assets
 
 import (
 	"bytes"
-	"compress/gzip"
-	"encoding/hex"
+	"context"
+	"encoding/json"
 	"fmt"
 	"html/template"
-	"io"
 	"net/http"
 	"path"
-	"regexp"
 	"sort"
 	"strings"
-
-	"github.com/golang/glog"
 )
 
-var varyHeaderRegexp = regexp.MustCompile("\\s*,\\s*")
-
-type gzipResponseWriter struct {
-	io.Writer
-	http.ResponseWriter
-	sniffDone bool
-}
-
-func (w *gzipResponseWriter) Write(b []byte) (int, error) {
-	if !w.sniffDone {
-		if w.Header().Get("Content-Type") == "" {
-			w.Header().Set("Content-Type", http.DetectContentType(b))
-		}
-		w.sniffDone = true
-	}
-	return w.Writer.Write(b)
-}
-
-// GzipHandler wraps a http.Handler to support transparent gzip encoding.
-func GzipHandler(h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Add("Vary", "Accept-Encoding")
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-			h.ServeHTTP(w, r)
-			return
-		}
-		// Normalize the Accept-Encoding header for improved caching
-		r.Header.Set("Accept-Encoding", "gzip")
-		w.Header().Set("Content-Encoding", "gzip")
-		gz := gzip.NewWriter(w)
-		defer gz.Close()
-		h.ServeHTTP(&gzipResponseWriter{Writer: gz, ResponseWriter: w}, r)
-	})
-}
-
-func generateEtag(r *http.Request, version string, varyHeaders []string) string {
-	varyHeaderValues := ""
-	for _, varyHeader := range varyHeaders {
-		varyHeaderValues += r.Header.Get(varyHeader)
-	}
-	return fmt.Sprintf("W/\"%s_%s\"", version, hex.EncodeToString([]byte(varyHeaderValues)))
-}
-
-func CacheControlHandler(version string, h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		vary := w.Header().Get("Vary")
-		varyHeaders := []string{}
-		if vary != "" {
-			varyHeaders = varyHeaderRegexp.Split(vary, -1)
-		}
-		etag := generateEtag(r, version, varyHeaders)
-
-		if r.Header.Get("If-None-Match") == etag {
-			w.WriteHeader(http.StatusNotModified)
-			return
-		}
-
-		w.Header().Add("ETag", etag)
-		h.ServeHTTP(w, r)
-
-	})
-}
-
 type LongestToShortest []string
 
 func (s LongestToShortest) Len() int {
@@ -91,6 +24,31 @@ func (s LongestToShortest) Less(i, j int) bool {
 	return len(s[i]) > len(s[j])
 }
 
+// HTML5ModeOptions controls the supply-chain-integrity features HTML5ModeHandler
+// layers onto the served index pages.
+type HTML5ModeOptions struct {
+	// EnableSRI computes sha384 integrity digests for every <script src> and
+	// <link rel="stylesheet"> the index references that can be resolved via Asset(...),
+	// and adds integrity/crossorigin attributes to those tags.
+	EnableSRI bool
+	// CSPTemplate is the Content-Security-Policy header value to emit, with
+	// "{{CSP_NONCE}}" standing in for the per-request nonce. Both the header and any
+	// occurrences of "{{CSP_NONCE}}" in the served HTML (e.g. inline script nonce
+	// attributes) are substituted with the same per-request value. Empty disables CSP.
+	CSPTemplate string
+	// ExtraCSPDirectives are appended, semicolon-separated, to CSPTemplate.
+	ExtraCSPDirectives []string
+	// Metrics, if set, is notified every time a request falls through to a
+	// subcontext's index page instead of an actual asset.
+	Metrics HTML5Metrics
+}
+
+// HTML5Metrics receives a count of every index-fallback HTML5ModeHandler serves, keyed
+// by subcontext, so callers can track how often users are landing on client routes.
+type HTML5Metrics interface {
+	ObserveIndexFallback(subcontext string)
+}
+
 // HTML5ModeHandler will serve any static assets we know about, all other paths
 // are assumed to be HTML5 paths for the console application and index.html will
 // be served.
@@ -98,7 +56,7 @@ func (s LongestToShortest) Less(i, j int) bool {
 //
 // subcontextMap is a map of keys (subcontexts, no leading or trailing slashes) to the asset path (no
 // leading slash) to serve for that subcontext if a resource that does not exist is requested
-func HTML5ModeHandler(contextRoot string, subcontextMap map[string]string, h http.Handler) (http.Handler, error) {
+func HTML5ModeHandler(contextRoot string, subcontextMap map[string]string, opts HTML5ModeOptions, h http.Handler) (http.Handler, error) {
 	subcontextData := map[string][]byte{}
 	subcontexts := []string{}
 
@@ -107,7 +65,11 @@ func HTML5ModeHandler(contextRoot string, subcontextMap map[string]string, h htt
 		if err != nil {
 			return nil, err
 		}
-		b = bytes.Replace(b, []byte(`<base href="/">`), []byte(fmt.Sprintf(`<base href="%s">`, path.Join(contextRoot, subcontext))), 1)
+		basePath := path.Join(contextRoot, subcontext)
+		b = bytes.Replace(b, []byte(`<base href="/">`), []byte(fmt.Sprintf(`<base href="%s">`, basePath)), 1)
+		if opts.EnableSRI {
+			b = injectSRI(b, basePath)
+		}
 		subcontextData[subcontext] = b
 		subcontexts = append(subcontexts, subcontext)
 	}
@@ -123,7 +85,10 @@ func HTML5ModeHandler(contextRoot string, subcontextMap map[string]string, h htt
 			// find the index we want to serve instead
 			for _, subcontext := range subcontexts {
 				if urlPath == subcontext || strings.HasPrefix(urlPath, subcontext+"/") {
-					w.Write(subcontextData[subcontext])
+					if opts.Metrics != nil {
+						opts.Metrics.ObserveIndexFallback(subcontext)
+					}
+					writeIndexWithCSP(w, r, subcontextData[subcontext], opts)
 					return
 				}
 			}
@@ -132,7 +97,29 @@ func HTML5ModeHandler(contextRoot string, subcontextMap map[string]string, h htt
 	}), nil
 }
 
-var configTemplate = template.Must(template.New("webConsoleConfig").Parse(`
+// writeIndexWithCSP substitutes a fresh CSP nonce into the prebuilt index page and
+// emits the matching Content-Security-Policy header, or writes the page unmodified if
+// no CSP template is configured.
+func writeIndexWithCSP(w http.ResponseWriter, r *http.Request, index []byte, opts HTML5ModeOptions) {
+	if opts.CSPTemplate == "" {
+		w.Write(index)
+		return
+	}
+
+	nonce, err := newCSPNonce()
+	if err != nil {
+		DefaultLogger.Errorf(r.Context(), "Unable to generate CSP nonce: %v", err)
+		w.Write(index)
+		return
+	}
+
+	w.Header().Set("Content-Security-Policy", renderCSPHeader(opts.CSPTemplate, nonce, opts.ExtraCSPDirectives))
+	w.Write(bytes.Replace(index, []byte(cspNoncePlaceholder), []byte(nonce), -1))
+}
+
+var configTemplate = template.Must(template.New("webConsoleConfig").Funcs(template.FuncMap{
+	"json": marshalJSON,
+}).Parse(`
 window.OPENSHIFT_CONFIG = {
   api: {
     openshift: {
@@ -149,10 +136,24 @@ window.OPENSHIFT_CONFIG = {
   	oauth_redirect_base: "{{ .OAuthRedirectBase | js}}",
   	oauth_client_id: "{{ .OAuthClientID | js}}",
   	logout_uri: "{{ .LogoutURI | js}}",
-  }
+  	{{ if .OIDC }}oidc: {{ .OIDC | json }},{{ end }}
+  },
+  extensions: {{ .Extensions | json }}
 };
 `))
 
+// marshalJSON renders v as JSON for embedding directly into the config.js template;
+// it never errors toward the template since an encoding failure there would produce a
+// broken config.js with no way to report it to the caller.
+func marshalJSON(v interface{}) template.HTML {
+	b, err := json.Marshal(v)
+	if err != nil {
+		DefaultLogger.Errorf(context.Background(), "Unable to render config value as JSON: %v", err)
+		return "{}"
+	}
+	return template.HTML(b)
+}
+
 type WebConsoleConfig struct {
 	// MasterAddr is the host:port the UI should call the master API on. Scheme is derived from the scheme the UI is served on, so they must be the same.
 	MasterAddr string
@@ -171,17 +172,11 @@ type WebConsoleConfig struct {
 	OAuthClientID string
 	// LogoutURI is an optional (absolute) URI to redirect to after completing a logout. If not specified, the built-in logout page is shown.
 	LogoutURI string
-}
-
-func GeneratedConfigHandler(config WebConsoleConfig, h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.TrimPrefix(r.URL.Path, "/") == "config.js" {
-			w.Header().Add("Cache-Control", "no-cache, no-store")
-			if err := configTemplate.Execute(w, config); err != nil {
-				glog.Errorf("Unable to render config template: %v", err)
-			}
-			return
-		}
-		h.ServeHTTP(w, r)
-	})
+	// OIDC holds endpoints and parameters discovered from an OpenID Connect issuer's
+	// /.well-known/openid-configuration document. Set by OIDCDiscoveryProvider; nil
+	// means the console should use the static OAuthAuthorizeURI flow above instead.
+	OIDC *OIDCConfig
+	// Extensions holds additional fields downstream consoles want rendered into
+	// config.js without requiring a code change to WebConsoleConfig itself.
+	Extensions map[string]interface{}
 }
@@ -0,0 +1,79 @@
+package assets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/golang/glog"
+)
+
+// Logger is a structured error logger the assets package routes its diagnostics
+// through, so callers can plug in zap, slog, or anything else instead of glog. ctx is
+// passed through so an implementation can pull a request ID (see RequestIDHandler) or
+// other trace context out and attach it to the log line.
+type Logger interface {
+	Errorf(ctx context.Context, format string, args ...interface{})
+}
+
+// DefaultLogger is used by every handler in this package unless replaced. It preserves
+// the historical glog.Errorf behavior, prefixed with the request ID when one is present
+// on ctx.
+var DefaultLogger Logger = glogLogger{}
+
+type glogLogger struct{}
+
+func (glogLogger) Errorf(ctx context.Context, format string, args ...interface{}) {
+	requestID, ok := RequestIDFromContext(ctx)
+	f, a := prefixWithRequestID(requestID, ok, format, args)
+	glog.Errorf(f, a...)
+}
+
+// prefixWithRequestID prepends a "[request_id=...]" verb (never literal text) to format
+// when a request ID is present, passing requestID as an ordinary argument. This keeps an
+// attacker-supplied X-Request-Id (see RequestIDHandler, which trusts it unconditionally)
+// from ever being parsed as Printf verbs against args.
+func prefixWithRequestID(requestID string, ok bool, format string, args []interface{}) (string, []interface{}) {
+	if !ok {
+		return format, args
+	}
+	return "[request_id=%s] " + format, append([]interface{}{requestID}, args...)
+}
+
+type requestIDContextKey struct{}
+
+// RequestIDHeader is the header used both to accept a caller-supplied request ID and to
+// echo back the one RequestIDHandler generated.
+const RequestIDHeader = "X-Request-Id"
+
+// RequestIDHandler ensures every request has a request ID: it trusts an inbound
+// X-Request-Id header if present, otherwise generates one, stores it on the request
+// context for Logger implementations and downstream handlers to pick up, and echoes it
+// back on the response.
+func RequestIDHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+		ctx := context.WithValue(r.Context(), requestIDContextKey{}, requestID)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFromContext returns the request ID RequestIDHandler attached to ctx, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDContextKey{}).(string)
+	return requestID, ok
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,30 @@
+package assets
+
+import "testing"
+
+// TestPrefixWithRequestIDDoesNotInterpretRequestIDAsFormatVerbs guards against CWE-134:
+// RequestIDHandler trusts an inbound X-Request-Id header unconditionally, so the request
+// ID must always be passed as an argument, never concatenated into the format string.
+func TestPrefixWithRequestIDDoesNotInterpretRequestIDAsFormatVerbs(t *testing.T) {
+	maliciousRequestID := "%s%x%p evil"
+
+	format, args := prefixWithRequestID(maliciousRequestID, true, "something failed: %v", []interface{}{"boom"})
+
+	if len(args) != 2 || args[0] != maliciousRequestID || args[1] != "boom" {
+		t.Fatalf("expected requestID and original args to be passed through as arguments, got %v", args)
+	}
+	wantFormat := "[request_id=%s] something failed: %v"
+	if format != wantFormat {
+		t.Errorf("prefixWithRequestID() format = %q, want %q", format, wantFormat)
+	}
+}
+
+func TestPrefixWithRequestIDNoRequestID(t *testing.T) {
+	format, args := prefixWithRequestID("", false, "something failed: %v", []interface{}{"boom"})
+	if format != "something failed: %v" {
+		t.Errorf("prefixWithRequestID() format = %q, want unchanged format", format)
+	}
+	if len(args) != 1 || args[0] != "boom" {
+		t.Errorf("prefixWithRequestID() args = %v, want original args unchanged", args)
+	}
+}
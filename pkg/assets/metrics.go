@@ -0,0 +1,128 @@
+package assets
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "openshift_web_console"
+
+// Metrics holds the Prometheus collectors for the asset-serving chain
+// (CompressionHandler -> CacheControlHandler -> HTML5ModeHandler -> GeneratedConfigHandler)
+// and doubles as the CompressionMetricsRecorder and HTML5Metrics those handlers expect,
+// so a single value wires observability through the whole chain.
+type Metrics struct {
+	requestDuration    *prometheus.HistogramVec
+	responseSize       *prometheus.HistogramVec
+	cacheResult        *prometheus.CounterVec
+	compressionRatio   *prometheus.HistogramVec
+	indexFallbackTotal *prometheus.CounterVec
+}
+
+// NewMetrics constructs the asset-serving collectors and registers them on reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "assets",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of asset requests in seconds.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"status"}),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "assets",
+			Name:      "response_size_bytes",
+			Help:      "Size of asset responses in bytes, as written to the client.",
+			Buckets:   prometheus.ExponentialBuckets(128, 4, 10),
+		}, []string{"status"}),
+		cacheResult: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "assets",
+			Name:      "cache_result_total",
+			Help:      "Count of asset requests by whether they were served from cache (304) or in full (200).",
+		}, []string{"result"}),
+		compressionRatio: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "assets",
+			Name:      "compression_ratio",
+			Help:      "Ratio of compressed to raw response size (compressed/raw) for compressed responses.",
+			Buckets:   []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1.0},
+		}, []string{"encoding"}),
+		indexFallbackTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "assets",
+			Name:      "index_fallback_total",
+			Help:      "Count of requests served the subcontext's index.html because no matching asset existed.",
+		}, []string{"subcontext"}),
+	}
+
+	reg.MustRegister(m.requestDuration, m.responseSize, m.cacheResult, m.compressionRatio, m.indexFallbackTotal)
+	return m
+}
+
+// metricsResponseWriter records the status code and bytes written so Handler can
+// observe them after the wrapped chain finishes.
+type metricsResponseWriter struct {
+	http.ResponseWriter
+	status       int
+	bytesWritten int
+}
+
+func (w *metricsResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *metricsResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytesWritten += n
+	return n, err
+}
+
+// Handler wraps h, recording request duration, response size, and cache-hit (304) vs.
+// full-response (200) rate for every request that passes through it.
+func (m *Metrics) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		mw := &metricsResponseWriter{ResponseWriter: w}
+
+		h.ServeHTTP(mw, r)
+
+		status := strconv.Itoa(mw.status)
+		m.requestDuration.WithLabelValues(status).Observe(time.Since(start).Seconds())
+		m.responseSize.WithLabelValues(status).Observe(float64(mw.bytesWritten))
+
+		switch mw.status {
+		case http.StatusNotModified:
+			m.cacheResult.WithLabelValues("hit").Inc()
+		case http.StatusOK:
+			m.cacheResult.WithLabelValues("miss").Inc()
+		}
+	})
+}
+
+// MetricsHandler is a convenience wrapper for callers that don't need the
+// CompressionMetricsRecorder/HTML5Metrics hooks a *Metrics also provides.
+func MetricsHandler(reg prometheus.Registerer, h http.Handler) http.Handler {
+	return NewMetrics(reg).Handler(h)
+}
+
+// ObserveCompression implements CompressionMetricsRecorder.
+func (m *Metrics) ObserveCompression(encoding string, rawBytes, compressedBytes int) {
+	if rawBytes <= 0 {
+		return
+	}
+	m.compressionRatio.WithLabelValues(encoding).Observe(float64(compressedBytes) / float64(rawBytes))
+}
+
+// ObserveIndexFallback implements HTML5Metrics.
+func (m *Metrics) ObserveIndexFallback(subcontext string) {
+	m.indexFallbackTotal.WithLabelValues(subcontext).Inc()
+}
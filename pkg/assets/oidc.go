@@ -0,0 +1,179 @@
+package assets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// defaultOIDCRefreshInterval is how often the discovery document is re-fetched when the
+// issuer's response carries no (or a non-positive) Cache-Control max-age.
+const defaultOIDCRefreshInterval = 1 * time.Hour
+
+// OIDCConfig is the subset of an OpenID Connect discovery document the web console
+// needs to drive its authorization code + PKCE login flow, rendered into config.js.
+type OIDCConfig struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	EndSessionEndpoint    string   `json:"end_session_endpoint,omitempty"`
+	JWKSURI               string   `json:"jwks_uri"`
+	ScopesSupported       []string `json:"scopes_supported,omitempty"`
+	CodeChallengeMethod   string   `json:"code_challenge_method"`
+}
+
+// oidcDiscoveryDocument mirrors the fields OpenID Connect Discovery 1.0 requires or
+// commonly provides that this package cares about; unknown fields are ignored.
+type oidcDiscoveryDocument struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	EndSessionEndpoint            string   `json:"end_session_endpoint"`
+	JWKSURI                       string   `json:"jwks_uri"`
+	ScopesSupported               []string `json:"scopes_supported"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+// OIDCDiscoveryProvider decorates a base ConfigProvider, overlaying auth endpoints
+// discovered from issuerURL's /.well-known/openid-configuration document so the
+// console can federate with any standards-compliant IdP without a rebuild.
+type OIDCDiscoveryProvider struct {
+	base       ConfigProvider
+	issuerURL  string
+	httpClient *http.Client
+	current    atomic.Value // *OIDCConfig
+}
+
+// NewOIDCDiscoveryProvider fetches issuerURL's discovery document once synchronously,
+// then refreshes it periodically (honoring the response's Cache-Control max-age until
+// ctx is cancelled) in the background.
+func NewOIDCDiscoveryProvider(ctx context.Context, base ConfigProvider, issuerURL string) (*OIDCDiscoveryProvider, error) {
+	p := &OIDCDiscoveryProvider{
+		base:       base,
+		issuerURL:  strings.TrimRight(issuerURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	ttl, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	go p.refreshLoop(ctx, ttl)
+	return p, nil
+}
+
+func (p *OIDCDiscoveryProvider) refreshLoop(ctx context.Context, ttl time.Duration) {
+	timer := time.NewTimer(ttl)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			next, err := p.refresh(ctx)
+			if err != nil {
+				DefaultLogger.Errorf(ctx, "Unable to refresh OIDC discovery document from %s: %v", p.issuerURL, err)
+				next = defaultOIDCRefreshInterval
+			}
+			timer.Reset(next)
+		}
+	}
+}
+
+// refresh fetches and stores the current discovery document, returning how long the
+// caller should wait before fetching it again.
+func (p *OIDCDiscoveryProvider) refresh(ctx context.Context) (time.Duration, error) {
+	doc, ttl, err := p.fetchDiscoveryDocument(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	codeChallengeMethod := "S256"
+	if len(doc.CodeChallengeMethodsSupported) > 0 && !containsString(doc.CodeChallengeMethodsSupported, "S256") {
+		codeChallengeMethod = doc.CodeChallengeMethodsSupported[0]
+	}
+
+	p.current.Store(&OIDCConfig{
+		Issuer:                doc.Issuer,
+		AuthorizationEndpoint: doc.AuthorizationEndpoint,
+		TokenEndpoint:         doc.TokenEndpoint,
+		EndSessionEndpoint:    doc.EndSessionEndpoint,
+		JWKSURI:               doc.JWKSURI,
+		ScopesSupported:       doc.ScopesSupported,
+		CodeChallengeMethod:   codeChallengeMethod,
+	})
+
+	return ttl, nil
+}
+
+func (p *OIDCDiscoveryProvider) fetchDiscoveryDocument(ctx context.Context) (*oidcDiscoveryDocument, time.Duration, error) {
+	req, err := http.NewRequest(http.MethodGet, p.issuerURL+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("discovery request to %s returned %s", p.issuerURL, resp.Status)
+	}
+
+	var doc oidcDiscoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, 0, fmt.Errorf("unable to parse discovery document from %s: %v", p.issuerURL, err)
+	}
+
+	return &doc, cacheTTLFromResponse(resp), nil
+}
+
+// cacheTTLFromResponse honors the discovery response's Cache-Control max-age, falling
+// back to defaultOIDCRefreshInterval when it is absent or non-positive.
+func cacheTTLFromResponse(resp *http.Response) time.Duration {
+	for _, directive := range strings.Split(resp.Header.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(directive)
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+		seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+		if err != nil || seconds <= 0 {
+			continue
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultOIDCRefreshInterval
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the base provider's config with OIDC populated from the most recently
+// discovered document.
+func (p *OIDCDiscoveryProvider) Get(ctx context.Context) (WebConsoleConfig, error) {
+	config, err := p.base.Get(ctx)
+	if err != nil {
+		return config, err
+	}
+	if oidc, ok := p.current.Load().(*OIDCConfig); ok {
+		config.OIDC = oidc
+		config.OAuthAuthorizeURI = oidc.AuthorizationEndpoint
+	}
+	return config, nil
+}
@@ -0,0 +1,42 @@
+package assets
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCacheTTLFromResponse(t *testing.T) {
+	cases := []struct {
+		name         string
+		cacheControl string
+		want         time.Duration
+	}{
+		{"max-age present", "max-age=120", 120 * time.Second},
+		{"max-age among other directives", "no-transform, max-age=60, public", 60 * time.Second},
+		{"zero max-age falls back", "max-age=0", defaultOIDCRefreshInterval},
+		{"negative max-age falls back", "max-age=-5", defaultOIDCRefreshInterval},
+		{"non-numeric max-age falls back", "max-age=soon", defaultOIDCRefreshInterval},
+		{"absent falls back", "", defaultOIDCRefreshInterval},
+	}
+
+	for _, c := range cases {
+		resp := &http.Response{Header: http.Header{}}
+		if c.cacheControl != "" {
+			resp.Header.Set("Cache-Control", c.cacheControl)
+		}
+		if got := cacheTTLFromResponse(resp); got != c.want {
+			t.Errorf("%s: cacheTTLFromResponse() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestContainsString(t *testing.T) {
+	values := []string{"S256", "plain"}
+	if !containsString(values, "S256") {
+		t.Errorf("expected containsString to find %q in %v", "S256", values)
+	}
+	if containsString(values, "S384") {
+		t.Errorf("expected containsString not to find %q in %v", "S384", values)
+	}
+}
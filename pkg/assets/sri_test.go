@@ -0,0 +1,38 @@
+package assets
+
+import "testing"
+
+func TestAssetPathForSrc(t *testing.T) {
+	cases := []struct {
+		name         string
+		src          string
+		basePath     string
+		wantPath     string
+		wantResolved bool
+	}{
+		{"relative to base", "scripts/app.js", "/console/admin/", "scripts/app.js", true},
+		{"absolute under custom context root", "/console/admin/scripts/app.js", "/console/admin/", "scripts/app.js", true},
+		{"absolute under default context root", "/console/scripts/app.js", "/console/", "scripts/app.js", true},
+		{"external url", "https://cdn.example.com/app.js", "/console/", "", false},
+		{"protocol-relative url", "//cdn.example.com/app.js", "/console/", "", false},
+	}
+
+	for _, c := range cases {
+		gotPath, ok := assetPathForSrc(c.src, c.basePath)
+		if ok != c.wantResolved {
+			t.Errorf("%s: assetPathForSrc(%q, %q) ok = %v, want %v", c.name, c.src, c.basePath, ok, c.wantResolved)
+			continue
+		}
+		if ok && gotPath != c.wantPath {
+			t.Errorf("%s: assetPathForSrc(%q, %q) = %q, want %q", c.name, c.src, c.basePath, gotPath, c.wantPath)
+		}
+	}
+}
+
+func TestRenderCSPHeader(t *testing.T) {
+	got := renderCSPHeader("script-src 'nonce-{{CSP_NONCE}}'", "abc123", []string{"object-src 'none'"})
+	want := "script-src 'nonce-abc123'; object-src 'none'"
+	if got != want {
+		t.Errorf("renderCSPHeader() = %q, want %q", got, want)
+	}
+}